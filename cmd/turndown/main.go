@@ -1,12 +1,14 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
-	"path/filepath"
 	"time"
 
+	"github.com/spf13/pflag"
+
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -19,12 +21,65 @@ import (
 	"github.com/kubecost/cluster-turndown/v2/pkg/signals"
 	"github.com/kubecost/cluster-turndown/v2/pkg/turndown"
 	"github.com/kubecost/cluster-turndown/v2/pkg/turndown/provider"
+	"github.com/kubecost/cluster-turndown/v2/pkg/turndown/reconciler"
 	"github.com/kubecost/cluster-turndown/v2/pkg/turndown/strategy"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
+// NodePoolScopeEnvVar, when set, holds a JSON-encoded provider.NodePoolScope that
+// restricts turndown to a subset of the cluster's node pools. Unset (or empty)
+// means no scope: every node pool GetNodePools() returns is turned down, matching
+// today's default behavior.
+//
+// This applies one scope process-wide, to every TurndownSchedule this controller
+// runs; a scope cannot be set per schedule. See the NodePoolScope doc comment in
+// pkg/turndown/provider/scope.go.
+const NodePoolScopeEnvVar = "TURNDOWN_NODE_POOL_SCOPE"
+
+// loadNodePoolScope reads and validates NodePoolScopeEnvVar, returning a nil scope
+// if it isn't set.
+func loadNodePoolScope() (*provider.NodePoolScope, error) {
+	raw := os.Getenv(NodePoolScopeEnvVar)
+	if raw == "" {
+		return nil, nil
+	}
+
+	scope := &provider.NodePoolScope{}
+	if err := json.Unmarshal([]byte(raw), scope); err != nil {
+		return nil, fmt.Errorf("Failed to parse %s: %s", NodePoolScopeEnvVar, err.Error())
+	}
+
+	if err := scope.Validate(); err != nil {
+		return nil, err
+	}
+
+	return scope, nil
+}
+
+// options holds the CLI flags used to configure kubeconfig resolution and logging.
+type options struct {
+	kubeconfig string
+	context    string
+	master     string
+	namespace  string
+	logLevel   string
+}
+
+func parseFlags() *options {
+	opts := &options{}
+
+	pflag.StringVar(&opts.kubeconfig, "kubeconfig", "", "Path to a kubeconfig file. Overrides KUBECONFIG and the default loading rules.")
+	pflag.StringVar(&opts.context, "context", "", "The name of the kubeconfig context to use.")
+	pflag.StringVar(&opts.master, "master", "", "The address of the Kubernetes API server. Overrides any value in kubeconfig.")
+	pflag.StringVar(&opts.namespace, "namespace", "", "The namespace to use, overriding the one set in kubeconfig context.")
+	pflag.StringVar(&opts.logLevel, "log-level", "info", "Log level: debug, info, warn, error, fatal, panic.")
+	pflag.Parse()
+
+	return opts
+}
+
 // Run web server with turndown endpoints
 func runWebServer(kubeClient kubernetes.Interface, client clientset.Interface, scheduler *turndown.TurndownScheduler, manager turndown.TurndownManager, provider provider.TurndownProvider) {
 	mux := http.NewServeMux()
@@ -37,26 +92,33 @@ func runWebServer(kubeClient kubernetes.Interface, client clientset.Interface, s
 	log.Fatal().Msgf("%s", http.ListenAndServe(":9731", mux))
 }
 
-// Initialize Kubernetes Client as well as the CRD Client
-func initKubernetes(isLocal bool) (kubernetes.Interface, clientset.Interface, error) {
-	var kc *rest.Config
-	var err error
+// Initialize Kubernetes Client as well as the CRD Client. When running in-cluster (no
+// kubeconfig resolvable via flags, KUBECONFIG, or the default loading rules), falls back
+// to rest.InClusterConfig(). Otherwise, builds configuration the same way kubectl does,
+// so --context/--master/--namespace behave as expected against any cluster.
+func initKubernetes(opts *options) (kubernetes.Interface, clientset.Interface, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if opts.kubeconfig != "" {
+		loadingRules.ExplicitPath = opts.kubeconfig
+	}
 
-	// For local testing, use kubeconfig in home directory
-	if isLocal {
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			return nil, nil, err
-		}
+	overrides := &clientcmd.ConfigOverrides{
+		Context: clientcmd.Context{
+			Namespace: opts.namespace,
+		},
+	}
+	if opts.context != "" {
+		overrides.CurrentContext = opts.context
+	}
+	if opts.master != "" {
+		overrides.ClusterInfo.Server = opts.master
+	}
 
-		configFile := filepath.Join(homeDir, ".kube", "config")
-		log.Info().Msgf("KubeConfig Path: %s", configFile)
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides)
 
-		kc, err = clientcmd.BuildConfigFromFlags("", configFile)
-		if err != nil {
-			return nil, nil, err
-		}
-	} else {
+	kc, err := clientConfig.ClientConfig()
+	if err != nil {
+		log.Info().Msg("No kubeconfig resolved, falling back to in-cluster config")
 		kc, err = rest.InClusterConfig()
 		if err != nil {
 			return nil, nil, err
@@ -90,28 +152,44 @@ func runTurndownResourceController(kubeClient kubernetes.Interface, tdClient cli
 	}(controller, stopCh)
 }
 
-// For now, we'll choose our strategy based on the provider, but functionally, there is
-// no dependency.
-func strategyForProvider(c kubernetes.Interface, p provider.TurndownProvider) (strategy.TurndownStrategy, error) {
+// Runs a Scheduler that periodically checks the cloud provider's reported node pool
+// sizes against the sizes the turndown manager last asked for, and re-applies the
+// desired sizing if it has drifted (e.g. a manual resize, or autoscaler intervention).
+func runReconciler(turndownProvider provider.TurndownProvider, desired *reconciler.DesiredState, stopCh <-chan struct{}) {
+	reconciler.NewScheduler(turndownProvider, desired, reconciler.DefaultInterval).Run(stopCh)
+}
+
+// strategyForProvider chooses a strategy based on raw's concrete type, but builds it
+// against effective, so callers can pass a decorator (e.g. reconciler.RecordingProvider)
+// that the rest of the application should actually drive node pool changes through.
+func strategyForProvider(c kubernetes.Interface, raw provider.TurndownProvider, effective provider.TurndownProvider) (strategy.TurndownStrategy, error) {
 	m := make(map[string]string)
 
-	switch v := p.(type) {
+	switch raw.(type) {
 	case *provider.GKEProvider:
-		return strategy.NewMasterlessTurndownStrategy(c, p, m), nil
+		return strategy.NewMasterlessTurndownStrategy(c, effective, m), nil
 	case *provider.EKSProvider:
-		return strategy.NewMasterlessTurndownStrategy(c, p, m), nil
+		return strategy.NewMasterlessTurndownStrategy(c, effective, m), nil
 	case *provider.AWSProvider:
-		return strategy.NewStandardTurndownStrategy(c, p), nil
+		return strategy.NewStandardTurndownStrategy(c, effective), nil
+	case *provider.AKSProvider:
+		return strategy.NewMasterlessTurndownStrategy(c, effective, m), nil
 	default:
-		return nil, fmt.Errorf("No strategy available for: %+v", v)
+		return nil, fmt.Errorf("No strategy available for: %+v", raw)
 	}
 }
 
 func main() {
 	zerolog.TimeFieldFormat = time.RFC3339
 	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339})
-	// TODO: Make configurable
-	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	opts := parseFlags()
+
+	logLevel, err := zerolog.ParseLevel(opts.logLevel)
+	if err != nil {
+		log.Fatal().Msgf("Invalid --log-level %q: %s", opts.logLevel, err.Error())
+	}
+	zerolog.SetGlobalLevel(logLevel)
 
 	stopCh := signals.SetupSignalHandler()
 
@@ -119,7 +197,7 @@ func main() {
 	log.Info().Msgf("Running Kubecost Turndown on: %s", node)
 
 	// Setup Components
-	kubeClient, tdClient, err := initKubernetes(false)
+	kubeClient, tdClient, err := initKubernetes(opts)
 	if err != nil {
 		log.Fatal().Msgf("Failed to initialize kubernetes client: %s", err.Error())
 	}
@@ -149,20 +227,47 @@ func main() {
 		return
 	}
 
+	// Restrict turndown to a subset of node pools, if TURNDOWN_NODE_POOL_SCOPE is set
+	nodePoolScope, err := loadNodePoolScope()
+	if err != nil {
+		log.Error().Msgf("Failed to load %s: %s", NodePoolScopeEnvVar, err.Error())
+		return
+	}
+
+	// Desired node pool sizing, updated on every SetNodePoolSizes/ResetNodePoolSizes
+	// call below, and read back by the reconciler to detect drift
+	reconcilerDesiredState := reconciler.NewDesiredState()
+
+	// Every node pool mutation from here on should go through recordingProvider, so
+	// that the reconciler observes the sizing the rest of the app asked for
+	recordingProvider := reconciler.NewRecordingProvider(turndownProvider, reconcilerDesiredState)
+
+	// scopedProvider wraps recordingProvider, not the other way around, so that when
+	// it floors a SetNodePoolSizes call to nodePoolScope.PreserveMinNodes,
+	// recordingProvider records the floored size actually applied rather than the
+	// size the caller originally asked for. GetNodePools/SetNodePoolSizes from here
+	// on only ever see the pools nodePoolScope selects (all of them, if
+	// nodePoolScope is nil).
+	scopedProvider := provider.NewScopedProvider(recordingProvider, nodePoolScope)
+
 	// Determine the best turndown strategy to use based on provider
-	strategy, err := strategyForProvider(kubeClient, turndownProvider)
+	strategy, err := strategyForProvider(kubeClient, turndownProvider, scopedProvider)
 	if err != nil {
 		log.Error().Msgf("Failed to create strategy: %s", err.Error())
 		return
 	}
 
 	// Turndown Management and Scheduler
-	manager := turndown.NewKubernetesTurndownManager(kubeClient, turndownProvider, strategy, node)
+	manager := turndown.NewKubernetesTurndownManager(kubeClient, scopedProvider, strategy, node)
 	scheduler := turndown.NewTurndownScheduler(manager, scheduleStore)
 
 	// Run TurndownSchedule Kubernetes Resource Controller
 	runTurndownResourceController(kubeClient, tdClient, scheduler, stopCh)
 
+	// Run periodic drift reconciliation against the desired node pool sizes recorded
+	// whenever node pools are scaled
+	runReconciler(scopedProvider, reconcilerDesiredState, stopCh)
+
 	// Run Turndown Endpoints
-	runWebServer(kubeClient, tdClient, scheduler, manager, turndownProvider)
+	runWebServer(kubeClient, tdClient, scheduler, manager, scopedProvider)
 }
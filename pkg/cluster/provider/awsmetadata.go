@@ -0,0 +1,258 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	AWSMetaDataTokenEndpoint      = "http://169.254.169.254/latest/api/token"
+	AWSMetaDataDocumentEndpoint   = "http://169.254.169.254/latest/dynamic/instance-identity/document"
+	AWSMetaDataAZEndpoint         = "http://169.254.169.254/latest/meta-data/placement/availability-zone"
+	AWSMetaDataInstanceIDEndpoint = "http://169.254.169.254/latest/meta-data/instance-id"
+
+	AWSMetaDataTokenTTLHeader = "X-aws-ec2-metadata-token-ttl-seconds"
+	AWSMetaDataTokenHeader    = "X-aws-ec2-metadata-token"
+	AWSMetaDataTokenTTL       = "21600"
+
+	// AWSMetaDataTokenCacheTTL is how long a fetched IMDSv2 token is reused before
+	// token() fetches a new one. Kept a little under the 21600s TTL requested via
+	// AWSMetaDataTokenTTL so a cached token is never handed out after IMDS has
+	// already expired it.
+	AWSMetaDataTokenCacheTTL = 21000 * time.Second
+
+	// AWSMetaDataCacheTTL bounds how long resolved metadata values (account,
+	// region, zone, cluster ID) are reused before md.document()/md.get() are
+	// called again.
+	AWSMetaDataCacheTTL = 5 * time.Minute
+
+	AWSMetaDataTokenKey     = "token"
+	AWSMetaDataAccountIDKey = "accountid"
+	AWSMetaDataRegionKey    = "region"
+	AWSMetaDataZoneKey      = "zone"
+	AWSMetaDataClusterIDKey = "cluster-id"
+
+	EKSClusterNameTagKey = "eks:cluster-name"
+)
+
+// instanceIdentityDocument is the subset of the IMDSv2 instance-identity
+// document we care about for resolving account/region.
+type instanceIdentityDocument struct {
+	AccountID string `json:"accountId"`
+	Region    string `json:"region"`
+}
+
+// AWSMetaData is the CloudMetadata implementation for AWS EC2/EKS, backed by
+// IMDSv2 (session-token-authenticated metadata requests) and the EC2
+// DescribeTags API for resolving the EKS cluster name tag.
+type AWSMetaData struct {
+	client *http.Client
+	cache  *ttlCache
+}
+
+func NewAWSMetaData() *AWSMetaData {
+	return &AWSMetaData{
+		client: &http.Client{
+			Transport: UserAgentTransport{
+				userAgent: KubecostTurndownUserAgent,
+				base:      http.DefaultTransport,
+			},
+		},
+		cache: newTTLCache(),
+	}
+}
+
+// token returns a cached IMDSv2 session token, fetching a new one if none is
+// cached or the cached one has passed AWSMetaDataTokenCacheTTL.
+func (md *AWSMetaData) token() (string, error) {
+	if tok, ok := md.cache.Get(AWSMetaDataTokenKey); ok {
+		return tok, nil
+	}
+
+	req, err := http.NewRequest(http.MethodPut, AWSMetaDataTokenEndpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set(AWSMetaDataTokenTTLHeader, AWSMetaDataTokenTTL)
+
+	resp, err := md.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("IMDSv2 token request returned status: %d", resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	tok := string(body)
+	md.cache.Set(AWSMetaDataTokenKey, tok, AWSMetaDataTokenCacheTTL)
+	return tok, nil
+}
+
+// get performs an authenticated IMDSv2 GET against the given metadata path.
+func (md *AWSMetaData) get(url string) (string, error) {
+	tok, err := md.token()
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set(AWSMetaDataTokenHeader, tok)
+
+	resp, err := md.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("IMDSv2 request to %s returned status: %d", url, resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	return string(body), nil
+}
+
+func (md *AWSMetaData) document() (*instanceIdentityDocument, error) {
+	body, err := md.get(AWSMetaDataDocumentEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc instanceIdentityDocument
+	if err := json.Unmarshal([]byte(body), &doc); err != nil {
+		return nil, err
+	}
+
+	return &doc, nil
+}
+
+// ProjectID does not apply to AWS; accounts are exposed via AccountID instead.
+func (md *AWSMetaData) ProjectID() string {
+	return ""
+}
+
+func (md *AWSMetaData) AccountID() string {
+	if v, ok := md.cache.Get(AWSMetaDataAccountIDKey); ok {
+		return v
+	}
+
+	doc, err := md.document()
+	if err != nil {
+		log.Error().Msgf("Getting Account ID: %s", err.Error())
+		return ""
+	}
+
+	md.cache.Set(AWSMetaDataAccountIDKey, doc.AccountID, AWSMetaDataCacheTTL)
+	return doc.AccountID
+}
+
+func (md *AWSMetaData) Region() string {
+	if v, ok := md.cache.Get(AWSMetaDataRegionKey); ok {
+		return v
+	}
+
+	doc, err := md.document()
+	if err != nil {
+		log.Error().Msgf("Getting Region: %s", err.Error())
+		return ""
+	}
+
+	md.cache.Set(AWSMetaDataRegionKey, doc.Region, AWSMetaDataCacheTTL)
+	return doc.Region
+}
+
+func (md *AWSMetaData) Zone() string {
+	if v, ok := md.cache.Get(AWSMetaDataZoneKey); ok {
+		return v
+	}
+
+	az, err := md.get(AWSMetaDataAZEndpoint)
+	if err != nil {
+		log.Error().Msgf("Getting Availability Zone: %s", err.Error())
+		return ""
+	}
+
+	md.cache.Set(AWSMetaDataZoneKey, az, AWSMetaDataCacheTTL)
+	return az
+}
+
+// MasterZone does not apply to EKS; the control plane is fully managed.
+func (md *AWSMetaData) MasterZone() string {
+	return ""
+}
+
+// ClusterID resolves the EKS cluster name by looking up the "eks:cluster-name"
+// tag on this instance via the EC2 DescribeTags API.
+func (md *AWSMetaData) ClusterID() string {
+	if v, ok := md.cache.Get(AWSMetaDataClusterIDKey); ok {
+		return v
+	}
+
+	instanceID, err := md.get(AWSMetaDataInstanceIDEndpoint)
+	if err != nil {
+		log.Error().Msgf("Getting Instance ID: %s", err.Error())
+		return ""
+	}
+
+	region := md.Region()
+
+	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(region))
+	if err != nil {
+		log.Error().Msgf("Loading AWS config: %s", err.Error())
+		return ""
+	}
+
+	client := ec2.NewFromConfig(cfg)
+	out, err := client.DescribeTags(context.TODO(), &ec2.DescribeTagsInput{
+		Filters: []ec2types.Filter{
+			{
+				Name:   aws.String("resource-id"),
+				Values: []string{instanceID},
+			},
+			{
+				Name:   aws.String("key"),
+				Values: []string{EKSClusterNameTagKey},
+			},
+		},
+	})
+	if err != nil {
+		log.Error().Msgf("Describing tags for instance %s: %s", instanceID, err.Error())
+		return ""
+	}
+
+	for _, tag := range out.Tags {
+		if aws.ToString(tag.Key) == EKSClusterNameTagKey {
+			clusterID := aws.ToString(tag.Value)
+			md.cache.Set(AWSMetaDataClusterIDKey, clusterID, AWSMetaDataCacheTTL)
+			return clusterID
+		}
+	}
+
+	log.Error().Msgf("Failed to locate %s tag on instance %s", EKSClusterNameTagKey, instanceID)
+	return ""
+}
@@ -0,0 +1,193 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	AzureMetaDataEndpoint          = "http://169.254.169.254/metadata/instance?api-version=2021-02-01"
+	AzureMetaDataSubscriptionIDKey = "subscriptionid"
+	AzureMetaDataResourceGroupKey  = "resourcegroup"
+	AzureMetaDataClusterNameKey    = "cluster-name"
+	AzureMetaDataLocationKey       = "location"
+
+	// AzureMetaDataCacheTTL bounds how long resolved IMDS values are reused
+	// before get() is called again.
+	AzureMetaDataCacheTTL = 5 * time.Minute
+)
+
+// azureComputeMetadata mirrors the subset of the Azure IMDS "compute" document
+// we care about for resolving the AKS cluster a node belongs to.
+type azureComputeMetadata struct {
+	Compute struct {
+		SubscriptionID    string `json:"subscriptionId"`
+		ResourceGroupName string `json:"resourceGroupName"`
+		Location          string `json:"location"`
+		Tags              string `json:"tags"`
+	} `json:"compute"`
+}
+
+// AzureMetaData is the CloudMetadata implementation for Azure/AKS, backed by
+// the Instance Metadata Service (IMDS).
+type AzureMetaData struct {
+	client *http.Client
+	cache  *ttlCache
+}
+
+func NewAzureMetaData() *AzureMetaData {
+	return &AzureMetaData{
+		client: &http.Client{
+			Transport: UserAgentTransport{
+				userAgent: KubecostTurndownUserAgent,
+				base:      http.DefaultTransport,
+			},
+		},
+		cache: newTTLCache(),
+	}
+}
+
+func (md *AzureMetaData) get() (*azureComputeMetadata, error) {
+	req, err := http.NewRequest(http.MethodGet, AzureMetaDataEndpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata", "true")
+
+	resp, err := md.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("IMDS returned status: %d", resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var imds azureComputeMetadata
+	if err := json.Unmarshal(body, &imds); err != nil {
+		return nil, err
+	}
+
+	return &imds, nil
+}
+
+// ProjectID returns the Azure subscription ID, the closest analogue to a GCP project.
+func (md *AzureMetaData) ProjectID() string {
+	if v, ok := md.cache.Get(AzureMetaDataSubscriptionIDKey); ok {
+		return v
+	}
+
+	imds, err := md.get()
+	if err != nil {
+		log.Error().Msgf("Getting Subscription ID: %s", err.Error())
+		return ""
+	}
+
+	md.cache.Set(AzureMetaDataSubscriptionIDKey, imds.Compute.SubscriptionID, AzureMetaDataCacheTTL)
+	return imds.Compute.SubscriptionID
+}
+
+// AccountID does not apply to Azure; subscriptions are exposed via ProjectID instead.
+func (md *AzureMetaData) AccountID() string {
+	return ""
+}
+
+// ResourceGroup returns the Azure resource group the instance belongs to. This
+// has no GCE/AWS analogue, so it isn't part of CloudMetadata, but AKSProvider
+// needs it directly to scope AgentPool API calls.
+func (md *AzureMetaData) ResourceGroup() string {
+	if v, ok := md.cache.Get(AzureMetaDataResourceGroupKey); ok {
+		return v
+	}
+
+	imds, err := md.get()
+	if err != nil {
+		log.Error().Msgf("Getting Resource Group: %s", err.Error())
+		return ""
+	}
+
+	md.cache.Set(AzureMetaDataResourceGroupKey, imds.Compute.ResourceGroupName, AzureMetaDataCacheTTL)
+	return imds.Compute.ResourceGroupName
+}
+
+// Region returns the Azure location the instance is running in.
+func (md *AzureMetaData) Region() string {
+	if v, ok := md.cache.Get(AzureMetaDataLocationKey); ok {
+		return v
+	}
+
+	imds, err := md.get()
+	if err != nil {
+		log.Error().Msgf("Getting Location: %s", err.Error())
+		return ""
+	}
+
+	md.cache.Set(AzureMetaDataLocationKey, imds.Compute.Location, AzureMetaDataCacheTTL)
+	return imds.Compute.Location
+}
+
+// Zone is not exposed consistently across Azure regions/VM SKUs in the IMDS
+// compute document, so it mirrors Region rather than returning an empty value.
+func (md *AzureMetaData) Zone() string {
+	return md.Region()
+}
+
+// MasterZone does not apply to AKS; the control plane is fully managed.
+func (md *AzureMetaData) MasterZone() string {
+	return ""
+}
+
+// ClusterID parses the "aks-managed-cluster-name" tag out of the IMDS compute
+// tags field, which AKS stamps onto every VM in the node resource group.
+func (md *AzureMetaData) ClusterID() string {
+	if v, ok := md.cache.Get(AzureMetaDataClusterNameKey); ok {
+		return v
+	}
+
+	imds, err := md.get()
+	if err != nil {
+		log.Error().Msgf("Getting cluster-name tag: %s", err.Error())
+		return ""
+	}
+
+	for _, kv := range splitAzureTags(imds.Compute.Tags) {
+		if kv[0] == "aks-managed-cluster-name" {
+			md.cache.Set(AzureMetaDataClusterNameKey, kv[1], AzureMetaDataCacheTTL)
+			return kv[1]
+		}
+	}
+
+	log.Error().Msg("Failed to locate aks-managed-cluster-name tag in IMDS compute metadata")
+	return ""
+}
+
+// splitAzureTags parses the semicolon-delimited, colon-separated tag string
+// returned by IMDS, e.g. "foo:bar;aks-managed-cluster-name:my-cluster".
+func splitAzureTags(tags string) [][2]string {
+	var result [][2]string
+	start := 0
+	for i := 0; i <= len(tags); i++ {
+		if i == len(tags) || tags[i] == ';' {
+			pair := tags[start:i]
+			for j := 0; j < len(pair); j++ {
+				if pair[j] == ':' {
+					result = append(result, [2]string{pair[:j], pair[j+1:]})
+					break
+				}
+			}
+			start = i + 1
+		}
+	}
+	return result
+}
@@ -13,28 +13,20 @@ import (
 )
 
 const (
-	KubecostTurndownUserAgent = "cluster-turndown"
 	GKEMetaDataProjectIDKey   = "projectid"
 	GKEMetaDataZoneKey        = "zone"
 	GKEMetaDataMasterZoneKey  = "master-zone"
 	GKEMetaDataClusterNameKey = "cluster-name"
 )
 
+// GKEMetaData is the CloudMetadata implementation for GCE/GKE, backed by the
+// GCE metadata server. Resolved values are cached in a plain map for the life of
+// the process; unlike AWSMetaData and AzureMetaData, its entries never expire.
 type GKEMetaData struct {
 	client *metadata.Client
 	cache  map[string]string
 }
 
-type UserAgentTransport struct {
-	userAgent string
-	base      http.RoundTripper
-}
-
-func (t UserAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	req.Header.Set("User-Agent", t.userAgent)
-	return t.base.RoundTrip(req)
-}
-
 func NewGKEMetaData() *GKEMetaData {
 	c := metadata.NewClient(&http.Client{
 		Transport: UserAgentTransport{
@@ -49,7 +41,7 @@ func NewGKEMetaData() *GKEMetaData {
 	}
 }
 
-func (md *GKEMetaData) GetProjectID() string {
+func (md *GKEMetaData) ProjectID() string {
 	pid, ok := md.cache[GKEMetaDataProjectIDKey]
 	if ok {
 		return pid
@@ -65,7 +57,12 @@ func (md *GKEMetaData) GetProjectID() string {
 	return projectID
 }
 
-func (md *GKEMetaData) GetClusterID() string {
+// AccountID does not apply to GCE; GKE clusters are scoped by ProjectID instead.
+func (md *GKEMetaData) AccountID() string {
+	return ""
+}
+
+func (md *GKEMetaData) ClusterID() string {
 	cn, ok := md.cache[GKEMetaDataClusterNameKey]
 	if ok {
 		return cn
@@ -81,7 +78,7 @@ func (md *GKEMetaData) GetClusterID() string {
 	return attribute
 }
 
-func (md *GKEMetaData) GetMasterZone() string {
+func (md *GKEMetaData) MasterZone() string {
 	z, ok := md.cache[GKEMetaDataMasterZoneKey]
 	if ok {
 		return z
@@ -117,7 +114,7 @@ func (md *GKEMetaData) GetMasterZone() string {
 	}
 }
 
-func (md *GKEMetaData) GetZone() string {
+func (md *GKEMetaData) Zone() string {
 	z, ok := md.cache[GKEMetaDataZoneKey]
 	if ok {
 		return z
@@ -132,3 +129,18 @@ func (md *GKEMetaData) GetZone() string {
 	md.cache[GKEMetaDataZoneKey] = zone
 	return zone
 }
+
+// Region derives the region from the zone, e.g. "us-central1-a" -> "us-central1".
+func (md *GKEMetaData) Region() string {
+	zone := md.Zone()
+	if zone == "" {
+		return ""
+	}
+
+	idx := strings.LastIndex(zone, "-")
+	if idx == -1 {
+		return zone
+	}
+
+	return zone[:idx]
+}
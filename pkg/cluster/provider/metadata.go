@@ -0,0 +1,94 @@
+package provider
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+const KubecostTurndownUserAgent = "cluster-turndown"
+
+// CloudMetadata abstracts the instance metadata lookups each TurndownProvider
+// needs to resolve itself, so provider constructors depend on an interface
+// rather than calling cloud-specific SDKs/IMDS endpoints directly. Methods
+// that don't apply to a given cloud (e.g. AccountID() on GCE) return "".
+type CloudMetadata interface {
+	// ProjectID returns the GCP project ID or Azure subscription ID the
+	// instance belongs to.
+	ProjectID() string
+
+	// AccountID returns the AWS account ID the instance belongs to.
+	AccountID() string
+
+	// ClusterID returns the name of the cluster the instance is a member of.
+	ClusterID() string
+
+	// Zone returns the instance's availability zone.
+	Zone() string
+
+	// Region returns the instance's region.
+	Region() string
+
+	// MasterZone returns the zone of the cluster's control plane, for clusters
+	// that expose one. Managed control planes (EKS, AKS) return "".
+	MasterZone() string
+}
+
+// UserAgentTransport tags every outgoing request with the cluster-turndown
+// user agent, so all CloudMetadata implementations are identifiable to the
+// metadata services they call. AWSMetaData's EC2 DescribeTags call goes through
+// the AWS SDK's own client and does not use this transport.
+type UserAgentTransport struct {
+	userAgent string
+	base      http.RoundTripper
+}
+
+func (t UserAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.Header.Set("User-Agent", t.userAgent)
+	return t.base.RoundTrip(req)
+}
+
+// ttlCache is an in-memory string cache where each entry expires independently on
+// its own TTL, used by AWSMetaData and AzureMetaData so repeated lookups (e.g.
+// once per reconciliation tick) don't re-hit the metadata service for values that
+// rarely change, while still picking up changes eventually instead of caching for
+// the lifetime of the process.
+type ttlCache struct {
+	mu      sync.Mutex
+	entries map[string]ttlCacheEntry
+}
+
+type ttlCacheEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+func newTTLCache() *ttlCache {
+	return &ttlCache{
+		entries: make(map[string]ttlCacheEntry),
+	}
+}
+
+// Get returns the cached value for key, and false if it's missing or expired.
+func (c *ttlCache) Get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok || time.Now().After(e.expiresAt) {
+		return "", false
+	}
+
+	return e.value, true
+}
+
+// Set stores value for key, to be returned by Get until ttl elapses.
+func (c *ttlCache) Set(key, value string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = ttlCacheEntry{
+		value:     value,
+		expiresAt: time.Now().Add(ttl),
+	}
+}
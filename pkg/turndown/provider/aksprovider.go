@@ -0,0 +1,199 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v2"
+
+	cp "github.com/kubecost/cluster-turndown/v2/pkg/cluster/provider"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	AKSNodePoolLabel = "agentpool"
+
+	// AKSTurndownPoolName is the AKS agent pool name used for the singleton turndown
+	// pool. AKS requires agent pool names to be <= 12 lowercase alphanumeric
+	// characters (no hyphens), so this is distinct from TurndownNodeLabel, which is
+	// only ever used as a node label.
+	AKSTurndownPoolName = "turndown"
+)
+
+// AKSProvider is a TurndownProvider implementation for Azure Kubernetes Service.
+// Node pools are scaled via the AKS AgentPools API rather than a generic VMSS
+// client, since AKS owns the underlying scale sets and expects pool mutations to
+// go through its own control plane.
+type AKSProvider struct {
+	kubeClient      kubernetes.Interface
+	clusterProvider cp.ClusterProvider
+	metadata        *cp.AzureMetaData
+	agentPools      *armcontainerservice.AgentPoolsClient
+
+	subscriptionID string
+	resourceGroup  string
+	clusterName    string
+}
+
+// NewAKSProvider builds an AKSProvider from a previously-constructed AzureMetaData,
+// rather than resolving IMDS itself, so the metadata lookup can be shared/stubbed
+// independently of the provider that consumes it.
+func NewAKSProvider(kubeClient kubernetes.Interface, clusterProvider cp.ClusterProvider, metadata *cp.AzureMetaData) (*AKSProvider, error) {
+	subscriptionID := metadata.ProjectID()
+	resourceGroup := metadata.ResourceGroup()
+	clusterName := metadata.ClusterID()
+
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create Azure credential: %s", err.Error())
+	}
+
+	agentPools, err := armcontainerservice.NewAgentPoolsClient(subscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create AgentPools client: %s", err.Error())
+	}
+
+	return &AKSProvider{
+		kubeClient:      kubeClient,
+		clusterProvider: clusterProvider,
+		metadata:        metadata,
+		agentPools:      agentPools,
+		subscriptionID:  subscriptionID,
+		resourceGroup:   resourceGroup,
+		clusterName:     clusterName,
+	}, nil
+}
+
+// IsTurndownNodePool returns true if the current node is part of the dedicated
+// turndown system node pool, identified by the turndown node label.
+func (p *AKSProvider) IsTurndownNodePool() bool {
+	node := os.Getenv("NODE_NAME")
+	if node == "" {
+		return false
+	}
+
+	n, err := p.kubeClient.CoreV1().Nodes().Get(context.TODO(), node, metav1.GetOptions{})
+	if err != nil {
+		log.Error().Msgf("Failed to lookup node %s: %s", node, err.Error())
+		return false
+	}
+
+	_, ok := n.Labels[TurndownNodeLabel]
+	return ok
+}
+
+// CreateSingletonNodePool creates a single-node, system-mode AKS agent pool
+// labeled for turndown so the controller has somewhere stable to run from
+// while the rest of the cluster is scaled down.
+func (p *AKSProvider) CreateSingletonNodePool(labels map[string]string) error {
+	nodeLabels := toTurndownNodePoolLabels(labels)
+
+	pool := armcontainerservice.AgentPool{
+		Properties: &armcontainerservice.ManagedClusterAgentPoolProfileProperties{
+			Count:      to.Ptr[int32](1),
+			VMSize:     to.Ptr("Standard_DS2_v2"),
+			Mode:       to.Ptr(armcontainerservice.AgentPoolModeSystem),
+			OSType:     to.Ptr(armcontainerservice.OSTypeLinux),
+			NodeLabels: toStringPtrMap(nodeLabels),
+		},
+	}
+
+	poller, err := p.agentPools.BeginCreateOrUpdate(context.TODO(), p.resourceGroup, p.clusterName, AKSTurndownPoolName, pool, nil)
+	if err != nil {
+		return fmt.Errorf("Failed to create singleton node pool: %s", err.Error())
+	}
+
+	_, err = poller.PollUntilDone(context.TODO(), nil)
+	return err
+}
+
+// GetNodePools returns the AKS agent pools backing this cluster.
+func (p *AKSProvider) GetNodePools() ([]cp.NodePool, error) {
+	pager := p.agentPools.NewListPager(p.resourceGroup, p.clusterName, nil)
+
+	var pools []cp.NodePool
+	for pager.More() {
+		page, err := pager.NextPage(context.TODO())
+		if err != nil {
+			return nil, fmt.Errorf("Failed to list agent pools: %s", err.Error())
+		}
+
+		for _, ap := range page.Value {
+			if ap.Name == nil || ap.Properties == nil || ap.Properties.Count == nil {
+				continue
+			}
+
+			labels := make(map[string]string, len(ap.Properties.NodeLabels))
+			for k, v := range ap.Properties.NodeLabels {
+				if v != nil {
+					labels[k] = *v
+				}
+			}
+
+			pools = append(pools, cp.NodePool{
+				Name:      *ap.Name,
+				NodeCount: *ap.Properties.Count,
+				Labels:    labels,
+			})
+		}
+	}
+
+	return pools, nil
+}
+
+// GetPoolID returns the AKS agent pool name a given node belongs to, taken
+// from the well-known "agentpool" node label AKS applies to every VM.
+func (p *AKSProvider) GetPoolID(node *v1.Node) string {
+	return node.Labels[AKSNodePoolLabel]
+}
+
+// SetNodePoolSizes scales the provided agent pools to the given size.
+func (p *AKSProvider) SetNodePoolSizes(nodePools []cp.NodePool, size int32) error {
+	for _, pool := range nodePools {
+		resp, err := p.agentPools.Get(context.TODO(), p.resourceGroup, p.clusterName, pool.Name, nil)
+		if err != nil {
+			return fmt.Errorf("Failed to get agent pool %s: %s", pool.Name, err.Error())
+		}
+
+		resp.Properties.Count = to.Ptr(size)
+
+		poller, err := p.agentPools.BeginCreateOrUpdate(context.TODO(), p.resourceGroup, p.clusterName, pool.Name, resp.AgentPool, nil)
+		if err != nil {
+			return fmt.Errorf("Failed to scale agent pool %s: %s", pool.Name, err.Error())
+		}
+
+		if _, err := poller.PollUntilDone(context.TODO(), nil); err != nil {
+			return fmt.Errorf("Failed waiting for agent pool %s to scale: %s", pool.Name, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// ResetNodePoolSizes restores the provided node pools to their originally
+// reported sizes.
+func (p *AKSProvider) ResetNodePoolSizes(nodePools []cp.NodePool) error {
+	for _, pool := range nodePools {
+		if err := p.SetNodePoolSizes([]cp.NodePool{pool}, pool.NodeCount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func toStringPtrMap(m map[string]string) map[string]*string {
+	result := make(map[string]*string, len(m))
+	for k, v := range m {
+		result[k] = to.Ptr(v)
+	}
+	return result
+}
@@ -4,10 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"strings"
 
-	"cloud.google.com/go/compute/metadata"
-
 	cp "github.com/kubecost/cluster-turndown/v2/pkg/cluster/provider"
 
 	v1 "k8s.io/api/core/v1"
@@ -33,7 +32,10 @@ type TurndownProvider interface {
 	ResetNodePoolSizes(nodePools []cp.NodePool) error
 }
 
-// Creates a new TurndownProvider implementation using the kubernetes client instance a ClusterProvider
+// Creates a new TurndownProvider implementation using the kubernetes client instance a ClusterProvider.
+// The concrete implementation is selected from the providers registered via RegisterProvider: each
+// registered Detector is run against the cluster's nodes and the highest-confidence match wins. Set
+// TURNDOWN_PROVIDER to force selection of a specific registered provider by name.
 func NewTurndownProvider(client kubernetes.Interface, clusterProvider cp.ClusterProvider) (TurndownProvider, error) {
 	if client == nil {
 		return nil, fmt.Errorf("Could not create new TurndownProvider with nil Kubernetes client")
@@ -51,26 +53,36 @@ func NewTurndownProvider(client kubernetes.Interface, clusterProvider cp.Cluster
 		return nil, fmt.Errorf("Could not locate any Nodes in Kubernetes cluster.")
 	}
 
-	if metadata.OnGCE() {
-		return NewGKEProvider(client, clusterProvider), nil
+	if override := os.Getenv(TurndownProviderEnvVar); override != "" {
+		name := strings.ToLower(override)
+		reg, ok := providerRegistry[name]
+		if !ok {
+			return nil, fmt.Errorf("%s=%s does not match any registered provider", TurndownProviderEnvVar, override)
+		}
+
+		log.Info().Msgf("%s set, forcing provider: %s", TurndownProviderEnvVar, name)
+		return reg.factory(client, clusterProvider)
 	}
 
-	node := nodes.Items[0]
-	provider := strings.ToLower(node.Spec.ProviderID)
-	if strings.HasPrefix(provider, "aws") {
-		if _, ok := node.Labels["eks.amazonaws.com/nodegroup"]; ok {
-			log.Info().Msg("Found ProviderID starting with \"aws\" and eks nodegroup, using EKS Provider")
-			return NewEKSProvider(client, clusterProvider), nil
+	var bestName string
+	var bestScore int
+	var best *providerRegistration
+	for name, reg := range providerRegistry {
+		score := reg.detector(nodes.Items)
+		if score > bestScore {
+			bestName = name
+			bestScore = score
+			best = reg
 		}
-		log.Info().Msg("Found ProviderID starting with \"aws\", using AWS Provider")
-		return NewAWSProvider(client, clusterProvider), nil
-	} else if strings.HasPrefix(provider, "azure") {
-		log.Info().Msg("Found ProviderID starting with \"azure\", using Azure Provider")
-		return nil, errors.New("Azure Not Supported")
-	} else {
+	}
+
+	if best == nil {
 		log.Info().Msg("Unsupported provider, falling back to default")
 		return nil, errors.New("Custom Not Supported")
 	}
+
+	log.Info().Msgf("Detected provider: %s (confidence: %d)", bestName, bestScore)
+	return best.factory(client, clusterProvider)
 }
 
 // Utility function which creates a new map[string]string containing turndown labels in addition
@@ -0,0 +1,88 @@
+package provider
+
+import (
+	"strings"
+
+	"cloud.google.com/go/compute/metadata"
+
+	cp "github.com/kubecost/cluster-turndown/v2/pkg/cluster/provider"
+
+	v1 "k8s.io/api/core/v1"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// TurndownProviderEnvVar, when set, forces NewTurndownProvider to select a
+// specific registered provider by name rather than running detection. Useful
+// for local testing against a cluster where the metadata service isn't
+// reachable.
+const TurndownProviderEnvVar = "TURNDOWN_PROVIDER"
+
+// ProviderFactory constructs a TurndownProvider implementation from a
+// Kubernetes client and ClusterProvider.
+type ProviderFactory func(kubernetes.Interface, cp.ClusterProvider) (TurndownProvider, error)
+
+// Detector inspects the node list (and, indirectly, the metadata service) and
+// returns a confidence score indicating how likely it is that the registered
+// provider matches the running cluster. A score of 0 or less means "no match".
+type Detector func(nodes []v1.Node) int
+
+type providerRegistration struct {
+	factory  ProviderFactory
+	detector Detector
+}
+
+var providerRegistry = map[string]*providerRegistration{}
+
+// RegisterProvider registers a TurndownProvider implementation under name,
+// analogous to Kubernetes' cloudprovider.RegisterCloudProvider. Providers are
+// expected to call this from an init() function so that NewTurndownProvider
+// can select among them without a hard-coded dispatch.
+func RegisterProvider(name string, factory ProviderFactory, detector Detector) {
+	providerRegistry[name] = &providerRegistration{
+		factory:  factory,
+		detector: detector,
+	}
+}
+
+func init() {
+	RegisterProvider("gce", func(c kubernetes.Interface, p cp.ClusterProvider) (TurndownProvider, error) {
+		return NewGKEProvider(c, p, cp.NewGKEMetaData()), nil
+	}, func(nodes []v1.Node) int {
+		if metadata.OnGCE() {
+			return 100
+		}
+		return 0
+	})
+
+	RegisterProvider("eks", func(c kubernetes.Interface, p cp.ClusterProvider) (TurndownProvider, error) {
+		return NewEKSProvider(c, p, cp.NewAWSMetaData()), nil
+	}, func(nodes []v1.Node) int {
+		if len(nodes) == 0 || !strings.HasPrefix(strings.ToLower(nodes[0].Spec.ProviderID), "aws") {
+			return 0
+		}
+		if _, ok := nodes[0].Labels["eks.amazonaws.com/nodegroup"]; ok {
+			return 100
+		}
+		return 0
+	})
+
+	RegisterProvider("aws", func(c kubernetes.Interface, p cp.ClusterProvider) (TurndownProvider, error) {
+		return NewAWSProvider(c, p, cp.NewAWSMetaData()), nil
+	}, func(nodes []v1.Node) int {
+		if len(nodes) == 0 || !strings.HasPrefix(strings.ToLower(nodes[0].Spec.ProviderID), "aws") {
+			return 0
+		}
+		// Lower than "eks" so a node group label wins when both match.
+		return 50
+	})
+
+	RegisterProvider("azure", func(c kubernetes.Interface, p cp.ClusterProvider) (TurndownProvider, error) {
+		return NewAKSProvider(c, p, cp.NewAzureMetaData())
+	}, func(nodes []v1.Node) int {
+		if len(nodes) == 0 || !strings.HasPrefix(strings.ToLower(nodes[0].Spec.ProviderID), "azure") {
+			return 0
+		}
+		return 100
+	})
+}
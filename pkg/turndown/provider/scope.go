@@ -0,0 +1,123 @@
+package provider
+
+import (
+	"fmt"
+
+	cp "github.com/kubecost/cluster-turndown/v2/pkg/cluster/provider"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// NodePoolScope narrows a cluster's node pools down to the subset an operation
+// should target, rather than always operating on every pool GetNodePools() returns.
+// NodePoolSelector matches against node pool labels/tags, IncludeNames/ExcludeNames
+// allow explicit overrides, and PreserveMinNodes sets a floor instead of always
+// scaling matched pools to zero.
+//
+// A NodePoolScope is not part of the TurndownSchedule API, so it cannot be set per
+// schedule. cmd/turndown/main.go
+// applies a single NodePoolScope process-wide via ScopedProvider, read from the
+// TURNDOWN_NODE_POOL_SCOPE env var; every schedule shares that one scope.
+type NodePoolScope struct {
+	NodePoolSelector *metav1.LabelSelector `json:"nodePoolSelector,omitempty"`
+	IncludeNames     []string              `json:"includeNames,omitempty"`
+	ExcludeNames     []string              `json:"excludeNames,omitempty"`
+	PreserveMinNodes int32                 `json:"preserveMinNodes,omitempty"`
+}
+
+// Validate rejects a negative preserveMinNodes, a nodePoolSelector that can't be
+// converted to a label selector, and any node pool named in both includeNames and
+// excludeNames.
+func (s *NodePoolScope) Validate() error {
+	if s == nil {
+		return nil
+	}
+
+	if s.PreserveMinNodes < 0 {
+		return fmt.Errorf("scope.preserveMinNodes must be >= 0, got %d", s.PreserveMinNodes)
+	}
+
+	if s.NodePoolSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(s.NodePoolSelector); err != nil {
+			return fmt.Errorf("scope.nodePoolSelector is invalid: %s", err.Error())
+		}
+	}
+
+	for _, include := range s.IncludeNames {
+		for _, exclude := range s.ExcludeNames {
+			if include == exclude {
+				return fmt.Errorf("scope.includeNames and scope.excludeNames both reference node pool %q", include)
+			}
+		}
+	}
+
+	return nil
+}
+
+// FilterNodePools narrows pools down to the ones scope selects: nodePoolSelector
+// and includeNames are additive (either one matching includes the pool), then
+// excludeNames removes any pool named explicitly. A nil scope matches every pool,
+// preserving today's "operate on everything" behavior.
+func FilterNodePools(pools []cp.NodePool, scope *NodePoolScope) ([]cp.NodePool, error) {
+	if scope == nil {
+		return pools, nil
+	}
+
+	if err := scope.Validate(); err != nil {
+		return nil, err
+	}
+
+	var selector labels.Selector
+	if scope.NodePoolSelector != nil {
+		s, err := metav1.LabelSelectorAsSelector(scope.NodePoolSelector)
+		if err != nil {
+			return nil, err
+		}
+		selector = s
+	}
+
+	excluded := make(map[string]bool, len(scope.ExcludeNames))
+	for _, name := range scope.ExcludeNames {
+		excluded[name] = true
+	}
+
+	included := make(map[string]bool, len(scope.IncludeNames))
+	for _, name := range scope.IncludeNames {
+		included[name] = true
+	}
+
+	hasPositiveMatch := selector != nil || len(included) > 0
+
+	var filtered []cp.NodePool
+	for _, pool := range pools {
+		if excluded[pool.Name] {
+			continue
+		}
+
+		if !hasPositiveMatch {
+			filtered = append(filtered, pool)
+			continue
+		}
+
+		if included[pool.Name] {
+			filtered = append(filtered, pool)
+			continue
+		}
+
+		if selector != nil && selector.Matches(labels.Set(pool.Labels)) {
+			filtered = append(filtered, pool)
+		}
+	}
+
+	return filtered, nil
+}
+
+// FloorFor returns the minimum size a node pool governed by scope should be
+// scaled down to, instead of the usual zero.
+func (s *NodePoolScope) FloorFor(pool cp.NodePool) int32 {
+	if s == nil {
+		return 0
+	}
+	return s.PreserveMinNodes
+}
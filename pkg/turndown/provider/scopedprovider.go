@@ -0,0 +1,58 @@
+package provider
+
+import (
+	cp "github.com/kubecost/cluster-turndown/v2/pkg/cluster/provider"
+)
+
+// ScopedProvider wraps a TurndownProvider and narrows GetNodePools down to the
+// pools a NodePoolScope selects, so a partial turndown only ever sees/touches those
+// node pools. SetNodePoolSizes respects the scope's PreserveMinNodes floor rather
+// than always scaling to zero. A nil scope makes this a passthrough.
+//
+// main.go applies one ScopedProvider process-wide, so every schedule shares the
+// same scope (see NodePoolScope's doc comment).
+type ScopedProvider struct {
+	TurndownProvider
+	scope *NodePoolScope
+}
+
+// NewScopedProvider wraps turndownProvider so its node pools are filtered/floored
+// according to scope. scope may be nil, in which case this behaves exactly like
+// turndownProvider.
+func NewScopedProvider(turndownProvider TurndownProvider, scope *NodePoolScope) *ScopedProvider {
+	return &ScopedProvider{
+		TurndownProvider: turndownProvider,
+		scope:            scope,
+	}
+}
+
+func (p *ScopedProvider) GetNodePools() ([]cp.NodePool, error) {
+	pools, err := p.TurndownProvider.GetNodePools()
+	if err != nil {
+		return nil, err
+	}
+
+	return FilterNodePools(pools, p.scope)
+}
+
+// SetNodePoolSizes scales nodePools to size, except a pool governed by
+// scope.PreserveMinNodes is never scaled below that floor.
+func (p *ScopedProvider) SetNodePoolSizes(nodePools []cp.NodePool, size int32) error {
+	if p.scope == nil {
+		return p.TurndownProvider.SetNodePoolSizes(nodePools, size)
+	}
+
+	for _, pool := range nodePools {
+		floor := p.scope.FloorFor(pool)
+		effective := size
+		if effective < floor {
+			effective = floor
+		}
+
+		if err := p.TurndownProvider.SetNodePoolSizes([]cp.NodePool{pool}, effective); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package reconciler
+
+import (
+	"sync"
+
+	cp "github.com/kubecost/cluster-turndown/v2/pkg/cluster/provider"
+)
+
+// DesiredState records the node pool sizes the turndown manager last asked the
+// cloud provider to converge to, along with whether a turndown is currently
+// active. RecordingProvider calls Record after every successful
+// SetNodePoolSizes or ResetNodePoolSizes it forwards, so the Scheduler has
+// something to diff actual provider state against between schedule runs.
+type DesiredState struct {
+	mu     sync.RWMutex
+	sizes  map[string]int32
+	active bool
+}
+
+func NewDesiredState() *DesiredState {
+	return &DesiredState{
+		sizes: make(map[string]int32),
+	}
+}
+
+// Record stores the size the given node pools were just set to, and whether
+// turndown is active (as opposed to having been reset back to normal).
+func (d *DesiredState) Record(pools []cp.NodePool, size int32, active bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.active = active
+	for _, pool := range pools {
+		d.sizes[pool.Name] = size
+	}
+}
+
+// Get returns the last recorded desired size for a node pool, and whether one
+// has been recorded at all.
+func (d *DesiredState) Get(name string) (int32, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	size, ok := d.sizes[name]
+	return size, ok
+}
+
+// Active reports whether the most recent Record call reflected an active
+// turndown (true) or a reset back to normal sizing (false).
+func (d *DesiredState) Active() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.active
+}
@@ -0,0 +1,24 @@
+package reconciler
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	lastRunTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "cluster_turndown_reconciler_last_run_timestamp_seconds",
+		Help: "Unix timestamp at which the reconciler last started a run.",
+	})
+
+	driftEventsDetected = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "cluster_turndown_reconciler_drift_events_total",
+		Help: "Total number of node pools found drifted from their desired size.",
+	})
+
+	reconciliationsPerformed = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "cluster_turndown_reconciler_reconciliations_total",
+		Help: "Total number of times the reconciler re-applied desired node pool sizes.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(lastRunTimestamp, driftEventsDetected, reconciliationsPerformed)
+}
@@ -0,0 +1,46 @@
+package reconciler
+
+import (
+	cp "github.com/kubecost/cluster-turndown/v2/pkg/cluster/provider"
+	"github.com/kubecost/cluster-turndown/v2/pkg/turndown/provider"
+)
+
+// RecordingProvider wraps a TurndownProvider and records every size it's told to
+// converge node pools to into a DesiredState, so a Scheduler can later diff actual
+// provider state against it. Every other TurndownProvider method is forwarded
+// unchanged via the embedded interface.
+type RecordingProvider struct {
+	provider.TurndownProvider
+	desired *DesiredState
+}
+
+// NewRecordingProvider wraps turndownProvider so that its SetNodePoolSizes and
+// ResetNodePoolSizes calls are mirrored into desired. Callers should use the
+// returned provider everywhere the wrapped one would otherwise be used, so the
+// Scheduler reading from desired sees every sizing change.
+func NewRecordingProvider(turndownProvider provider.TurndownProvider, desired *DesiredState) *RecordingProvider {
+	return &RecordingProvider{
+		TurndownProvider: turndownProvider,
+		desired:          desired,
+	}
+}
+
+func (r *RecordingProvider) SetNodePoolSizes(nodePools []cp.NodePool, size int32) error {
+	if err := r.TurndownProvider.SetNodePoolSizes(nodePools, size); err != nil {
+		return err
+	}
+
+	r.desired.Record(nodePools, size, true)
+	return nil
+}
+
+func (r *RecordingProvider) ResetNodePoolSizes(nodePools []cp.NodePool) error {
+	if err := r.TurndownProvider.ResetNodePoolSizes(nodePools); err != nil {
+		return err
+	}
+
+	for _, pool := range nodePools {
+		r.desired.Record([]cp.NodePool{pool}, pool.NodeCount, false)
+	}
+	return nil
+}
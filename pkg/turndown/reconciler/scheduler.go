@@ -0,0 +1,133 @@
+package reconciler
+
+import (
+	"time"
+
+	cp "github.com/kubecost/cluster-turndown/v2/pkg/cluster/provider"
+	"github.com/kubecost/cluster-turndown/v2/pkg/turndown/provider"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultInterval is how often the Scheduler checks for drift between
+	// scheduled turndown/turnup events.
+	DefaultInterval = 60 * time.Second
+
+	// DefaultMaxBackoff bounds the exponential backoff applied between failed
+	// reconciliation attempts.
+	DefaultMaxBackoff = 10 * time.Minute
+)
+
+// Scheduler periodically compares the actual node pool sizes reported by a
+// TurndownProvider against the sizes last recorded in a DesiredState, and
+// re-applies the desired sizes if they've drifted -- e.g. because a node pool
+// was manually resized, or a cluster autoscaler re-added nodes, during a
+// turndown window.
+type Scheduler struct {
+	turndownProvider provider.TurndownProvider
+	desired          *DesiredState
+	interval         time.Duration
+	maxBackoff       time.Duration
+}
+
+// NewScheduler creates a Scheduler that reconciles turndownProvider against
+// desired every interval (DefaultInterval if interval is 0).
+func NewScheduler(turndownProvider provider.TurndownProvider, desired *DesiredState, interval time.Duration) *Scheduler {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	return &Scheduler{
+		turndownProvider: turndownProvider,
+		desired:          desired,
+		interval:         interval,
+		maxBackoff:       DefaultMaxBackoff,
+	}
+}
+
+// Run starts the reconciliation loop in a new goroutine, and returns
+// immediately. The loop exits once stopCh is closed.
+func (s *Scheduler) Run(stopCh <-chan struct{}) {
+	go s.run(stopCh)
+}
+
+func (s *Scheduler) run(stopCh <-chan struct{}) {
+	backoff := s.interval
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			log.Info().Msg("Reconciler: shutting down")
+			return
+		case <-ticker.C:
+			if err := s.reconcile(); err != nil {
+				log.Error().Msgf("Reconciler: reconciliation failed: %s", err.Error())
+
+				backoff *= 2
+				if backoff > s.maxBackoff {
+					backoff = s.maxBackoff
+				}
+
+				ticker.Reset(backoff)
+				continue
+			}
+
+			if backoff != s.interval {
+				backoff = s.interval
+				ticker.Reset(backoff)
+			}
+		}
+	}
+}
+
+// reconcile fetches the current node pools from the provider, diffs them
+// against the desired state, and re-applies the desired sizing for any pool
+// found to have drifted. It only runs while a turndown is active: once
+// DesiredState has been reset back to normal sizing, node pool changes (e.g.
+// from a cluster autoscaler) are expected and are not drift to correct.
+func (s *Scheduler) reconcile() error {
+	lastRunTimestamp.SetToCurrentTime()
+
+	if !s.desired.Active() {
+		return nil
+	}
+
+	pools, err := s.turndownProvider.GetNodePools()
+	if err != nil {
+		return err
+	}
+
+	var drifted []cp.NodePool
+	for _, pool := range pools {
+		desiredSize, ok := s.desired.Get(pool.Name)
+		if !ok || pool.NodeCount == desiredSize {
+			continue
+		}
+
+		drifted = append(drifted, pool)
+	}
+
+	if len(drifted) == 0 {
+		return nil
+	}
+
+	driftEventsDetected.Add(float64(len(drifted)))
+	log.Info().Msgf("Reconciler: detected drift on %d node pool(s), re-applying desired sizing", len(drifted))
+
+	// Re-apply each pool's own recorded desired size individually, rather than a
+	// single size for the whole batch, since DesiredState.Record may have stored a
+	// different size per pool (e.g. sizes recorded by separate SetNodePoolSizes or
+	// ResetNodePoolSizes calls that each covered only some pools).
+	for _, pool := range drifted {
+		desiredSize, _ := s.desired.Get(pool.Name)
+		if err := s.turndownProvider.SetNodePoolSizes([]cp.NodePool{pool}, desiredSize); err != nil {
+			return err
+		}
+	}
+
+	reconciliationsPerformed.Inc()
+	return nil
+}